@@ -0,0 +1,291 @@
+package typewriter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTW is a minimal Interface used to exercise WriteAll/Generate without
+// a real TypeWriter.
+type fakeTW struct{ name string }
+
+func (f fakeTW) Name() string              { return f.name }
+func (f fakeTW) Imports(Type) []ImportSpec { return nil }
+func (f fakeTW) Write(w io.Writer, t Type) error {
+	_, err := fmt.Fprintf(w, "var Generated%s = true\n", t.Name)
+	return err
+}
+
+// buildTaggerTW is a fakeTW that also implements BuildTagger, so tests can
+// exercise the //go:build emission in write without a real TypeWriter.
+type buildTaggerTW struct {
+	fakeTW
+	tags func(Type) []string
+}
+
+func (b buildTaggerTW) BuildTags(t Type) []string { return b.tags(t) }
+
+// memFS is an in-memory FileSystem, so tests don't touch disk.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newMemFS() *memFS { return &memFS{files: make(map[string][]byte)} }
+
+func (fs *memFS) Create(name string) (io.WriteCloser, error) {
+	return &memFile{fs: fs, name: name}, nil
+}
+
+func (fs *memFS) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.files[name]; !ok {
+		return nil, os.ErrNotExist
+	}
+	return nil, nil
+}
+
+type memFile struct {
+	fs   *memFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (f *memFile) Write(p []byte) (int, error) { return f.buf.Write(p) }
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	f.fs.files[f.name] = f.buf.Bytes()
+	return nil
+}
+
+func newTestApp(fs FileSystem, names ...string) *App {
+	p := &Package{name: "sample", path: "example.com/sample"}
+	for _, n := range names {
+		p.Types = append(p.Types, Type{Name: n, Pkg: p})
+	}
+
+	return &App{
+		Packages:    []*Package{p},
+		TypeWriters: []Interface{fakeTW{name: "gen"}},
+		Fs:          fs,
+	}
+}
+
+// TestWriteAllIsDeterministicallyOrdered guards the ordering guarantee
+// WriteAll makes despite generating files concurrently: the returned slice
+// is always sorted, regardless of which goroutine finishes first.
+func TestWriteAllIsDeterministicallyOrdered(t *testing.T) {
+	a := newTestApp(newMemFS(), "Zebra", "Apple", "Mango")
+
+	written, err := a.WriteAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"apple_gen.go", "mango_gen.go", "zebra_gen.go"}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("got %v, want %v", written, want)
+	}
+	if !sort.StringsAreSorted(written) {
+		t.Fatalf("expected sorted output, got %v", written)
+	}
+}
+
+func TestWriteAllWritesThroughFs(t *testing.T) {
+	fs := newMemFS()
+	a := newTestApp(fs, "Apple")
+
+	if _, err := a.WriteAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, ok := fs.files["apple_gen.go"]
+	if !ok {
+		t.Fatalf("expected apple_gen.go to be written, got %v", fs.files)
+	}
+	if !bytes.Contains(src, []byte("GeneratedApple")) {
+		t.Fatalf("generated source missing expected content: %s", src)
+	}
+}
+
+// TestDryRunDoesNotTouchFilesystem guards App.DryRun: WriteAll should report
+// the filenames it would have written without calling through to Fs.
+func TestDryRunDoesNotTouchFilesystem(t *testing.T) {
+	fs := newMemFS()
+	a := newTestApp(fs, "Apple")
+	a.DryRun = true
+
+	written, err := a.WriteAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(written, []string{"apple_gen.go"}) {
+		t.Fatalf("got %v, want [apple_gen.go]", written)
+	}
+	if len(fs.files) != 0 {
+		t.Fatalf("expected no files written in dry-run, got %v", fs.files)
+	}
+}
+
+func TestGenerateMatchesWriteAllOutput(t *testing.T) {
+	fs := newMemFS()
+	a := newTestApp(fs, "Apple", "Mango")
+
+	out, err := a.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := a.WriteAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != len(written) {
+		t.Fatalf("Generate produced %d files, WriteAll wrote %d", len(out), len(written))
+	}
+	for _, f := range written {
+		if _, ok := out[f]; !ok {
+			t.Fatalf("WriteAll wrote %s, missing from Generate's output", f)
+		}
+	}
+}
+
+// generatedRx mirrors the regex go/ast.IsGenerated matches against, so
+// this test pins down compatibility with that convention without requiring
+// a go/ast.IsGenerated call (and the Go version it implies).
+var generatedRx = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// TestWriteEmitsCanonicalGeneratedHeader checks that the byline write emits
+// matches the "Code generated ... DO NOT EDIT." convention that
+// go/ast.IsGenerated (and downstream linters, coverage tooling) recognize.
+func TestWriteEmitsCanonicalGeneratedHeader(t *testing.T) {
+	fs := newMemFS()
+	a := newTestApp(fs, "Apple")
+
+	out, err := a.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, ok := out["apple_gen.go"]
+	if !ok {
+		t.Fatalf("expected apple_gen.go in output, got %v", out)
+	}
+
+	lines := strings.Split(string(src), "\n")
+	if len(lines) == 0 || !generatedRx.MatchString(lines[0]) {
+		t.Fatalf("first line %q does not match go/ast.IsGenerated convention", lines[0])
+	}
+}
+
+// TestBuildTagsDedupedWithinType guards buildTags: a single Type reporting
+// duplicate tags should produce one deduplicated, order-preserving list.
+func TestBuildTagsDedupedWithinType(t *testing.T) {
+	tw := buildTaggerTW{
+		fakeTW: fakeTW{name: "gen"},
+		tags:   func(Type) []string { return []string{"integration", "linux", "integration"} },
+	}
+
+	tx := []Type{{Name: "Apple"}}
+	got, err := buildTags(tw, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"integration", "linux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildTags() = %v, want %v", got, want)
+	}
+}
+
+// TestBuildTagsAgreeingAcrossTypes checks that multiple Types reporting the
+// same tag set (in any order) for the same TypeWriter combine cleanly.
+func TestBuildTagsAgreeingAcrossTypes(t *testing.T) {
+	tw := buildTaggerTW{
+		fakeTW: fakeTW{name: "gen"},
+		tags: func(t Type) []string {
+			switch t.Name {
+			case "Apple":
+				return []string{"integration", "linux"}
+			case "Mango":
+				return []string{"linux", "integration"}
+			}
+			return nil
+		},
+	}
+
+	tx := []Type{{Name: "Apple"}, {Name: "Mango"}}
+	got, err := buildTags(tw, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"integration", "linux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildTags() = %v, want %v", got, want)
+	}
+}
+
+// TestBuildTagsRejectsDivergentConstraints guards against the bug where
+// buildTags unioned different Types' platform-exclusive tags (e.g. Apple's
+// "linux" and Mango's "darwin") into one impossible //go:build constraint.
+// Divergent tag sets between Types sharing a file must be reported as an
+// error instead of silently combined.
+func TestBuildTagsRejectsDivergentConstraints(t *testing.T) {
+	tw := buildTaggerTW{
+		fakeTW: fakeTW{name: "gen"},
+		tags: func(t Type) []string {
+			switch t.Name {
+			case "Apple":
+				return []string{"integration", "linux"}
+			case "Mango":
+				return []string{"linux", "darwin"}
+			}
+			return nil
+		},
+	}
+
+	tx := []Type{{Name: "Apple"}, {Name: "Mango"}}
+	if _, err := buildTags(tw, tx); err == nil {
+		t.Fatal("expected an error for Types with divergent BuildTags, got nil")
+	}
+}
+
+// TestWriteEmitsBuildTagConstraint checks that write, given a BuildTagger
+// TypeWriter, emits both the old (// +build) and new (//go:build) guard
+// syntax with the same constraint, placed above the package clause.
+func TestWriteEmitsBuildTagConstraint(t *testing.T) {
+	p := &Package{name: "sample", path: "example.com/sample"}
+	tw := buildTaggerTW{
+		fakeTW: fakeTW{name: "gen"},
+		tags:   func(Type) []string { return []string{"integration"} },
+	}
+	tx := []Type{{Name: "Apple", Pkg: p}}
+
+	var buf bytes.Buffer
+	if _, err := write(&buf, &App{Directive: "gen"}, p, tx, tw); err != nil {
+		t.Fatal(err)
+	}
+
+	src := buf.String()
+	if !strings.Contains(src, "//go:build integration\n") {
+		t.Fatalf("missing //go:build line:\n%s", src)
+	}
+	if !strings.Contains(src, "// +build integration\n") {
+		t.Fatalf("missing // +build line:\n%s", src)
+	}
+	if strings.Index(src, "//go:build") > strings.Index(src, "package sample") {
+		t.Fatalf("build tag constraint must precede the package clause:\n%s", src)
+	}
+}
@@ -0,0 +1,24 @@
+package typewriter
+
+import "os"
+
+// Config controls how NewApp discovers packages and types to operate on.
+// The zero value is equivalent to DefaultConfig: the current directory,
+// no file filter, and no build tags.
+type Config struct {
+	// Filter restricts which files within a package are considered, e.g. to
+	// exclude generated files. A nil Filter considers all files.
+	Filter func(os.FileInfo) bool
+
+	// Patterns are package patterns passed to the underlying loader, in the
+	// same form accepted by `go build` (e.g. ".", "./...", or an import
+	// path). A nil/empty Patterns defaults to the current directory.
+	Patterns []string
+
+	// Tags are build tags honored by the loader, equivalent to `go build -tags`.
+	Tags []string
+}
+
+// DefaultConfig loads the current directory only, with no file filter and
+// no additional build tags.
+var DefaultConfig = &Config{}
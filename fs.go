@@ -0,0 +1,21 @@
+package typewriter
+
+import (
+	"io"
+	"os"
+)
+
+// FileSystem abstracts the destination WriteAll writes generated code to,
+// so TypeWriters can be exercised without touching disk (see App.DryRun
+// and App.Generate).
+type FileSystem interface {
+	Create(name string) (io.WriteCloser, error)
+	Stat(name string) (os.FileInfo, error)
+}
+
+// osFileSystem is the default FileSystem, backed by the OS.
+type osFileSystem struct{}
+
+func (osFileSystem) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
@@ -0,0 +1,41 @@
+package typewriter
+
+import "sort"
+
+// ImportSpec represents a single import, optionally aliased.
+type ImportSpec struct {
+	Name string
+	Path string
+}
+
+// ImportSpecSet is a de-duplicated set of ImportSpecs, keyed by path.
+type ImportSpecSet struct {
+	specs map[string]ImportSpec
+}
+
+// NewImportSpecSet returns an empty ImportSpecSet.
+func NewImportSpecSet() *ImportSpecSet {
+	return &ImportSpecSet{specs: make(map[string]ImportSpec)}
+}
+
+// Add inserts i into the set, keyed by its Path.
+func (s *ImportSpecSet) Add(i ImportSpec) {
+	s.specs[i.Path] = i
+}
+
+// ToSlice returns the set's ImportSpecs sorted by Path, for deterministic
+// output.
+func (s *ImportSpecSet) ToSlice() []ImportSpec {
+	specs := make([]ImportSpec, 0, len(s.specs))
+	for _, i := range s.specs {
+		specs = append(specs, i)
+	}
+	sort.Sort(importSpecByPath(specs))
+	return specs
+}
+
+type importSpecByPath []ImportSpec
+
+func (s importSpecByPath) Len() int           { return len(s) }
+func (s importSpecByPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
+func (s importSpecByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,21 @@
+package typewriter
+
+import "io"
+
+// Interface is implemented by TypeWriters: packages that generate code for
+// a Type. TypeWriters register themselves via Register, typically in
+// init().
+type Interface interface {
+	Name() string
+	Imports(Type) []ImportSpec
+	Write(io.Writer, Type) error
+}
+
+// BuildTagger is an optional capability interface for TypeWriters whose
+// generated code should only be compiled under certain build tags, e.g.
+// because it depends on a type that's itself guarded by a tag. If a
+// TypeWriter implements BuildTagger, write emits a matching //go:build (and
+// // +build) constraint above the package clause.
+type BuildTagger interface {
+	BuildTags(Type) []string
+}
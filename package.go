@@ -0,0 +1,33 @@
+package typewriter
+
+// Package represents a single Go package being operated on, along with the
+// Types within it that carry a typewriter directive.
+type Package struct {
+	name string
+	path string
+
+	// dir is the directory the package's source files live in, so
+	// generated output lands alongside its source (and so two packages
+	// with same-named Types don't collide on a bare filename). Empty for
+	// a Package built directly as a struct literal rather than loaded via
+	// getPackages, in which case generated files fall back to the
+	// process's cwd, as they always have.
+	dir string
+
+	// singleFile indicates all generated code for this package should be
+	// collected into one file per TypeWriter, rather than one file per Type.
+	singleFile bool
+
+	Types []Type
+}
+
+// Name is the package's short name, e.g. "typewriter".
+func (p *Package) Name() string { return p.name }
+
+// Path is the package's import path.
+func (p *Package) Path() string { return p.path }
+
+// Dir is the directory the package's source files live in, e.g. what
+// getPackages derived from go/packages' Fset positions. Empty for a
+// Package that wasn't loaded via getPackages.
+func (p *Package) Dir() string { return p.dir }
@@ -0,0 +1,180 @@
+package typewriter
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// getPackages loads the packages named by conf.Patterns (or "." by default)
+// with golang.org/x/tools/go/packages, walks their syntax trees for type
+// declarations carrying a "+directive" doc comment, and returns one
+// *Package per loaded package containing the matching Types.
+//
+// This replaces an earlier go/parser-based directory walk: patterns may
+// span multiple directories (including "./..."), conf.Tags are honored via
+// -tags, and every Type's Type field carries a fully resolved go/types.Type
+// rather than bare source-level syntax.
+func getPackages(directive string, conf *Config) ([]*Package, error) {
+	patterns := conf.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax |
+			packages.NeedName | packages.NeedDeps,
+	}
+	if len(conf.Tags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(conf.Tags, ",")}
+	}
+
+	loaded, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("typewriter: loading %v: %w", patterns, err)
+	}
+
+	marker := "+" + directive
+
+	// shared across every parseTagTypes call this run, so a full-import-path
+	// tag reference (e.g. github.com/foo/bar.Thing) only pays for a
+	// packages.Load subprocess once per import path, not once per reference
+	cache := make(tagTypeCache)
+
+	var pkgs []*Package
+	for _, lp := range loaded {
+		for _, e := range lp.Errors {
+			return nil, fmt.Errorf("typewriter: %s: %w", lp.PkgPath, e)
+		}
+
+		p := &Package{name: lp.Name, path: lp.PkgPath}
+
+		for _, f := range lp.Syntax {
+			filename := lp.Fset.Position(f.Pos()).Filename
+
+			if p.dir == "" {
+				p.dir = filepath.Dir(filename)
+			}
+
+			if conf.Filter != nil {
+				fi, statErr := os.Stat(filename)
+				if statErr == nil && !conf.Filter(fi) {
+					continue
+				}
+			}
+
+			isTest := strings.HasSuffix(filename, "_test.go")
+			fileImports := importsByName(f, lp.Types)
+
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Doc == nil {
+					continue
+				}
+
+				tag, ok := findDirective(gd.Doc, marker)
+				if !ok {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+
+					t := Type{Name: ts.Name.Name, Tag: tag, Pkg: p}
+					if isTest {
+						t.test = "_test"
+					}
+
+					tagTypes, err := parseTagTypes(lp.Types, fileImports, tag, cache)
+					if err != nil {
+						return nil, fmt.Errorf("typewriter: %s.%s: %w", lp.PkgPath, t.Name, err)
+					}
+					t.TagTypes = tagTypes
+
+					if obj := lp.TypesInfo.Defs[ts.Name]; obj != nil {
+						t.Type = obj.Type()
+					}
+					p.Types = append(p.Types, t)
+				}
+			}
+		}
+
+		if len(p.Types) > 0 {
+			pkgs = append(pkgs, p)
+		}
+	}
+
+	return pkgs, nil
+}
+
+// importsByName maps each import in f to the local identifier that refers
+// to it within f: its alias, if one was written (`import tm "time"`), or
+// its package name otherwise. Blank (`_`) and dot (`.`) imports are
+// omitted, since a tag can't reference a type through either. This is keyed
+// off the file's actual *ast.ImportSpecs rather than pkg.Imports(), since
+// go/types.Package only exposes a package's canonical name, not the alias
+// a particular file imported it under.
+func importsByName(f *ast.File, pkg *types.Package) map[string]*types.Package {
+	byPath := make(map[string]*types.Package, len(pkg.Imports()))
+	for _, imp := range pkg.Imports() {
+		byPath[imp.Path()] = imp
+	}
+
+	names := make(map[string]*types.Package, len(f.Imports))
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		imp, ok := byPath[path]
+		if !ok {
+			continue
+		}
+
+		name := imp.Name()
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+
+		names[name] = imp
+	}
+
+	return names
+}
+
+// findDirective looks for a comment line beginning with marker (e.g.
+// "+test") within doc, and returns the remainder of that line as the tag.
+// marker must be followed by end-of-line or whitespace, so a differently
+// named directive that merely starts with marker (e.g. "+genx" against
+// marker "+gen") is not mistaken for a match.
+func findDirective(doc *ast.CommentGroup, marker string) (tag string, ok bool) {
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if !strings.HasPrefix(text, marker) {
+			continue
+		}
+
+		rest := text[len(marker):]
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && !unicode.IsSpace(r) {
+			continue
+		}
+
+		return strings.TrimSpace(rest), true
+	}
+	return "", false
+}
@@ -0,0 +1,166 @@
+package typewriter
+
+import (
+	"go/ast"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDirective(t *testing.T) {
+	cases := []struct {
+		name    string
+		comment string
+		marker  string
+		wantTag string
+		wantOK  bool
+	}{
+		{"bare marker, no tag", "// +gen", "+gen", "", true},
+		{"marker with tag", `// +gen foo:"Bar"`, "+gen", `foo:"Bar"`, true},
+		{"unrelated comment", "// just a comment", "+gen", "", false},
+		{"marker absent", "// +other", "+gen", "", false},
+		{
+			// a directive with a longer name that merely starts with marker
+			// must not be mistaken for marker itself
+			"longer directive name is not a prefix match",
+			"// +genx should-not-match",
+			"+gen",
+			"",
+			false,
+		},
+		{"marker followed by tab", "// +gen\tfoo", "+gen", "foo", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc := &ast.CommentGroup{List: []*ast.Comment{{Text: c.comment}}}
+
+			tag, ok := findDirective(doc, c.marker)
+			if ok != c.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, c.wantOK)
+			}
+			if tag != c.wantTag {
+				t.Fatalf("tag = %q, want %q", tag, c.wantTag)
+			}
+		})
+	}
+}
+
+// TestGetPackagesOnlyMatchesWholeDirective is an end-to-end regression test
+// for the findDirective word-boundary bug: a type tagged with a directive
+// that merely starts with the one being loaded for (e.g. "+genx" while
+// loading "gen") must not be picked up.
+func TestGetPackagesOnlyMatchesWholeDirective(t *testing.T) {
+	dir := t.TempDir()
+
+	const src = `package sample
+
+// +gen foo:"Bar"
+type Included struct{}
+
+// +genx should-not-match
+type Excluded struct{}
+`
+	if err := os.WriteFile(filepath.Join(dir, "sample.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module sample\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := getPackages("gen", &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("got %d packages, want 1: %+v", len(pkgs), pkgs)
+	}
+
+	var names []string
+	for _, ty := range pkgs[0].Types {
+		names = append(names, ty.Name)
+	}
+	if len(names) != 1 || names[0] != "Included" {
+		t.Fatalf("got Types %v, want [Included]", names)
+	}
+}
+
+// TestGetPackagesSetsDirPerPackage is a regression test for generated output
+// being silently dropped when two packages loaded via "./..." each produce
+// a Type with the same name: without a per-Package Dir, app.jobs() keyed
+// output by a bare filename shared across packages, so one package's
+// output clobbered the other's in the output map.
+func TestGetPackagesSetsDirPerPackage(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module multipkg\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sub := range []string{"a", "b"} {
+		dir := filepath.Join(root, sub)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		src := "package " + sub + "\n\n// +gen\ntype Foo struct{}\n"
+		if err := os.WriteFile(filepath.Join(dir, sub+".go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := getPackages("gen", &Config{Patterns: []string{"./..."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("got %d packages, want 2: %+v", len(pkgs), pkgs)
+	}
+
+	dirs := make(map[string]bool)
+	for _, p := range pkgs {
+		if p.Dir() == "" {
+			t.Fatalf("package %s has no Dir", p.Name())
+		}
+		dirs[p.Dir()] = true
+	}
+	if len(dirs) != 2 {
+		t.Fatalf("expected 2 distinct package dirs, got %v", dirs)
+	}
+
+	a := &App{Packages: pkgs, TypeWriters: []Interface{fakeTW{name: "gen"}}}
+	out, err := a.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("Generate() produced %d files, want 2: %v", len(out), keysOf(out))
+	}
+}
+
+func keysOf(m map[string][]byte) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
@@ -0,0 +1,204 @@
+package typewriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"runtime"
+	"sync"
+)
+
+// Plugin is an out-of-process TypeWriter: rather than registering via
+// Register in an init() func compiled into the host binary, a Plugin is
+// discovered at runtime by LoadPlugins, either as a Go plugin (.so) or as a
+// separate executable speaking the stdio protocol below.
+type Plugin interface {
+	Interface
+}
+
+// LoadPlugins discovers TypeWriters from paths and appends them to
+// a.TypeWriters. A path ending in ".so" is loaded as a Go plugin (supported
+// on linux/darwin only, see `go help buildmode=plugin`); any other path is
+// treated as an executable speaking the pluginRequest/pluginResponse
+// protocol over its stdin/stdout.
+func (a *App) LoadPlugins(paths []string) error {
+	for _, path := range paths {
+		var p Interface
+		var err error
+
+		if filepath.Ext(path) == ".so" {
+			p, err = loadGoPlugin(path)
+		} else {
+			p, err = loadExecPlugin(path, a.Directive)
+		}
+
+		if err != nil {
+			return fmt.Errorf("typewriter: loading plugin %s: %w", path, err)
+		}
+
+		a.TypeWriters = append(a.TypeWriters, p)
+	}
+
+	return nil
+}
+
+func loadGoPlugin(path string) (Interface, error) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return nil, fmt.Errorf("go plugins are not supported on %s", runtime.GOOS)
+	}
+
+	pl, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := pl.Lookup("TypeWriter")
+	if err != nil {
+		return nil, err
+	}
+
+	tw, ok := sym.(Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s: exported TypeWriter does not implement typewriter.Interface", path)
+	}
+
+	return tw, nil
+}
+
+// pluginRequest is sent to an exec plugin for each Interface method the
+// host needs to invoke: Name, Imports, or Write.
+//
+// Known limitation: unlike an in-process or Go-plugin TypeWriter, an exec
+// plugin only ever sees a Type's bare Name and its raw, unparsed Tag - none
+// of the go/types info the go/packages-based loader now resolves (fields,
+// methods, embedded types, TagTypes) crosses the process boundary. An exec
+// plugin is therefore limited to the same string-tag-parsing TypeWriters
+// have always done; reflecting on real type information requires writing
+// an in-process TypeWriter (or a Go plugin, which shares the host's
+// go/types.Type values directly).
+type pluginRequest struct {
+	Method    string `json:"method"`
+	Directive string `json:"directive,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+}
+
+// pluginResponse is decoded from an exec plugin's stdout.
+type pluginResponse struct {
+	Name    string       `json:"name,omitempty"`
+	Imports []ImportSpec `json:"imports,omitempty"`
+	Header  []byte       `json:"header,omitempty"`
+	Body    []byte       `json:"body,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// execPlugin adapts an executable speaking the stdio protocol to Interface.
+// The process is started once, at load time, and kept running for the
+// life of the App: each call encodes one pluginRequest to its stdin and
+// decodes one pluginResponse from its stdout, serialized by mu since Write
+// and Imports may be called concurrently for different Types (see
+// App.Generate). Name is resolved once at load time and cached, since
+// app.go's jobs() and write() both call it repeatedly just to build
+// filenames and bylines.
+//
+// Known limitation: the child process is never explicitly shut down - there
+// is no Close on App or execPlugin. It relies entirely on noticing EOF on
+// its stdin when the host process exits. That's fine for a one-shot
+// go generate CLI, but an App embedded in a longer-lived process (e.g. a
+// server that calls LoadPlugins once and WriteAll repeatedly) will leak one
+// subprocess per exec plugin for its own lifetime.
+type execPlugin struct {
+	path      string
+	directive string
+	name      string
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+func loadExecPlugin(path, directive string) (Interface, error) {
+	cmd := exec.Command(path)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &execPlugin{
+		path:      path,
+		directive: directive,
+		enc:       json.NewEncoder(stdin),
+		dec:       json.NewDecoder(stdout),
+	}
+
+	resp, err := p.call(pluginRequest{Method: "Name"})
+	if err != nil {
+		return nil, err
+	}
+	p.name = resp.Name
+
+	return p, nil
+}
+
+func (p *execPlugin) call(req pluginRequest) (pluginResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var resp pluginResponse
+	req.Directive = p.directive
+
+	if err := p.enc.Encode(req); err != nil {
+		return resp, fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	if err := p.dec.Decode(&resp); err != nil {
+		return resp, fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	if resp.Error != "" {
+		return resp, fmt.Errorf("%s: %s", p.path, resp.Error)
+	}
+
+	return resp, nil
+}
+
+func (p *execPlugin) Name() string {
+	return p.name
+}
+
+func (p *execPlugin) Imports(t Type) []ImportSpec {
+	resp, err := p.call(pluginRequest{Method: "Imports", Type: t.Name, Tag: t.Tag})
+	if err != nil {
+		return nil
+	}
+	return resp.Imports
+}
+
+func (p *execPlugin) Write(w io.Writer, t Type) error {
+	resp, err := p.call(pluginRequest{Method: "Write", Type: t.Name, Tag: t.Tag})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Header) > 0 {
+		if _, err := w.Write(resp.Header); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(resp.Body)
+	return err
+}
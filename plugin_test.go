@@ -0,0 +1,188 @@
+package typewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+// TestHelperProcess is not a real test; it's exec'd by the execPlugin tests
+// as a stand-in exec plugin, following the pattern documented at
+// https://pkg.go.dev/os/exec#Command (see golang.org/x/tools/internal/testenv
+// and cmd/go's own tests for prior art).
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	dec := json.NewDecoder(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+
+	for {
+		var req pluginRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		var resp pluginResponse
+		switch req.Method {
+		case "Name":
+			resp.Name = "helper"
+		case "Imports":
+			if req.Type == "Explode" {
+				resp.Error = "boom"
+				break
+			}
+			resp.Imports = []ImportSpec{{Path: "fmt"}}
+		case "Write":
+			if req.Type == "Explode" {
+				resp.Error = "boom"
+				break
+			}
+			resp.Header = []byte("// header\n")
+			resp.Body = []byte(fmt.Sprintf("var Generated%s = true\n", req.Type))
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// helperCommand returns an *exec.Cmd that re-execs the test binary into
+// TestHelperProcess, the same trick exec_test.go in the standard library
+// uses to stand in for a real subprocess without shipping one.
+func helperCommand(args ...string) *exec.Cmd {
+	cs := append([]string{"-test.run=TestHelperProcess", "--"}, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+	return cmd
+}
+
+// loadHelperPlugin starts the helper process directly, bypassing
+// loadExecPlugin's exec.Command(path) (which expects a path, not an
+// already-built *exec.Cmd).
+func loadHelperPlugin(t *testing.T, directive string) *execPlugin {
+	t.Helper()
+
+	cmd := helperCommand()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	p := &execPlugin{
+		path:      os.Args[0],
+		directive: directive,
+		enc:       json.NewEncoder(stdin),
+		dec:       json.NewDecoder(stdout),
+	}
+
+	resp, err := p.call(pluginRequest{Method: "Name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.name = resp.Name
+
+	return p
+}
+
+// TestExecPluginRoundTrip exercises the Name/Imports/Write protocol
+// end-to-end against a real child process.
+func TestExecPluginRoundTrip(t *testing.T) {
+	p := loadHelperPlugin(t, "gen")
+
+	if got, want := p.Name(), "helper"; got != want {
+		t.Fatalf("Name() = %q, want %q", got, want)
+	}
+
+	imports := p.Imports(Type{Name: "Apple"})
+	if len(imports) != 1 || imports[0].Path != "fmt" {
+		t.Fatalf("Imports() = %v, want [{Path: fmt}]", imports)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf, Type{Name: "Apple"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	want := "// header\nvar GeneratedApple = true\n"
+	if got != want {
+		t.Fatalf("Write() wrote %q, want %q", got, want)
+	}
+}
+
+// TestExecPluginErrorPropagation checks that an Error set on the plugin's
+// response surfaces as a Go error rather than being silently swallowed.
+func TestExecPluginErrorPropagation(t *testing.T) {
+	p := loadHelperPlugin(t, "gen")
+
+	var buf bytes.Buffer
+	err := p.Write(&buf, Type{Name: "Explode"})
+	if err == nil {
+		t.Fatal("expected an error from a plugin response with Error set, got nil")
+	}
+	if got, want := err.Error(), fmt.Sprintf("%s: boom", p.path); got != want {
+		t.Fatalf("err = %q, want %q", got, want)
+	}
+}
+
+// TestExecPluginConcurrentCalls guards the concurrency claim in execPlugin's
+// doc comment: Write and Imports may be called concurrently for different
+// Types, serialized by mu so requests and responses on the single stdio
+// pipe are never interleaved.
+func TestExecPluginConcurrentCalls(t *testing.T) {
+	p := loadHelperPlugin(t, "gen")
+
+	names := []string{"Apple", "Mango", "Zebra", "Kiwi", "Pear", "Fig", "Plum", "Lime"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	bodies := make([]string, len(names))
+
+	for i, n := range names {
+		i, n := i, n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if imports := p.Imports(Type{Name: n}); len(imports) != 1 || imports[0].Path != "fmt" {
+				errs[i] = fmt.Errorf("Imports(%s) = %v", n, imports)
+				return
+			}
+
+			var buf bytes.Buffer
+			if err := p.Write(&buf, Type{Name: n}); err != nil {
+				errs[i] = err
+				return
+			}
+			bodies[i] = buf.String()
+		}()
+	}
+	wg.Wait()
+
+	for i, n := range names {
+		if errs[i] != nil {
+			t.Fatalf("goroutine for %s: %v", n, errs[i])
+		}
+		want := fmt.Sprintf("// header\nvar Generated%s = true\n", n)
+		if bodies[i] != want {
+			t.Fatalf("goroutine for %s wrote %q, want %q", n, bodies[i], want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package typewriter
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// TagType is a directive tag value that names a concrete Go type, e.g. the
+// `time.Time` in `// +test Compare[time.Time]`. Name is the bracketed
+// reference exactly as written; Type and Pkg are resolved via go/types so
+// TypeWriters can emit correctly-qualified identifiers without hand-parsing
+// tag payloads.
+type TagType struct {
+	Name string
+	Type types.Type
+	Pkg  *types.Package
+}
+
+// tagTypeRe matches bracketed type references within a directive tag, e.g.
+// the "time.Time" in `Compare[time.Time]`.
+var tagTypeRe = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// tagTypeCache memoizes packages.Load calls made to resolve full-import-path
+// tag references (e.g. "github.com/foo/bar.Thing"), keyed by import path.
+// Without it, a repo with several Types - or several tags on one Type -
+// referencing the same external package would re-shell out to the
+// packages.Load subprocess once per reference; getPackages creates one
+// cache and threads it through every parseTagTypes call for the run.
+type tagTypeCache map[string]*types.Package
+
+// parseTagTypes scans tag for bracketed type references and resolves each
+// to a go/types.Type. pkg is the types.Package the tag's Type was declared
+// in, used to resolve bare references (e.g. "Thing"); imports maps the
+// local identifier a file refers to an imported package by - its alias if
+// one was declared, its name otherwise - to that package, used to resolve
+// references qualified by a short package name (e.g. "time.Time", or
+// "tm.Time" for a file with `import tm "time"`). References qualified by a
+// full import path (e.g. "github.com/foo/bar.Thing") are resolved by
+// loading that package directly, regardless of imports, via cache.
+func parseTagTypes(pkg *types.Package, imports map[string]*types.Package, tag string, cache tagTypeCache) ([]TagType, error) {
+	var out []TagType
+
+	for _, m := range tagTypeRe.FindAllStringSubmatch(tag, -1) {
+		ref := m[1]
+
+		t, tpkg, err := resolveTagType(pkg, imports, ref, cache)
+		if err != nil {
+			return nil, fmt.Errorf("typewriter: resolving tag type %q: %w", ref, err)
+		}
+
+		out = append(out, TagType{Name: ref, Type: t, Pkg: tpkg})
+	}
+
+	return out, nil
+}
+
+// resolveTagType resolves a single bracketed reference to a go/types.Type,
+// along with the types.Package it was found in (nil for predeclared types).
+func resolveTagType(pkg *types.Package, imports map[string]*types.Package, ref string, cache tagTypeCache) (types.Type, *types.Package, error) {
+	if ref == "struct{}" {
+		return types.NewStruct(nil, nil), nil, nil
+	}
+
+	idx := strings.LastIndex(ref, ".")
+	if idx < 0 {
+		if obj := types.Universe.Lookup(ref); obj != nil {
+			return obj.Type(), nil, nil
+		}
+		if obj := pkg.Scope().Lookup(ref); obj != nil {
+			return obj.Type(), pkg, nil
+		}
+		return nil, nil, fmt.Errorf("unknown type %s", ref)
+	}
+
+	pkgRef, name := ref[:idx], ref[idx+1:]
+
+	// a full import path, e.g. github.com/foo/bar.Thing
+	if strings.Contains(pkgRef, "/") {
+		tpkg, ok := cache[pkgRef]
+		if !ok {
+			loaded, err := packages.Load(&packages.Config{Mode: packages.NeedTypes | packages.NeedDeps}, pkgRef)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(loaded) == 0 || loaded[0].Types == nil {
+				return nil, nil, fmt.Errorf("package %s not found", pkgRef)
+			}
+
+			tpkg = loaded[0].Types
+			cache[pkgRef] = tpkg
+		}
+
+		obj := tpkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, nil, fmt.Errorf("%s: no such type %s", pkgRef, name)
+		}
+		return obj.Type(), tpkg, nil
+	}
+
+	// a short, possibly-aliased package name imported by the file the tag
+	// was written in, e.g. time.Time, or tm.Time for `import tm "time"`
+	if imp, ok := imports[pkgRef]; ok {
+		obj := imp.Scope().Lookup(name)
+		if obj == nil {
+			return nil, nil, fmt.Errorf("%s: no such type %s", imp.Path(), name)
+		}
+		return obj.Type(), imp, nil
+	}
+
+	return nil, nil, fmt.Errorf("package %q not imported", pkgRef)
+}
@@ -0,0 +1,129 @@
+package typewriter
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestResolveTagTypeStruct(t *testing.T) {
+	typ, pkg, err := resolveTagType(nil, nil, "struct{}", make(tagTypeCache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != nil {
+		t.Fatalf("expected nil pkg for struct{}, got %v", pkg)
+	}
+	if _, ok := typ.(*types.Struct); !ok {
+		t.Fatalf("expected *types.Struct, got %T", typ)
+	}
+}
+
+func TestResolveTagTypeBuiltin(t *testing.T) {
+	typ, pkg, err := resolveTagType(nil, nil, "int", make(tagTypeCache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != nil {
+		t.Fatalf("expected nil pkg for a predeclared type, got %v", pkg)
+	}
+	if typ.String() != "int" {
+		t.Fatalf("got %v, want int", typ)
+	}
+}
+
+func TestResolveTagTypeBareInPackage(t *testing.T) {
+	pkg := types.NewPackage("example.com/foo", "foo")
+	named := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Thing", nil), types.NewStruct(nil, nil), nil)
+	pkg.Scope().Insert(named.Obj())
+	pkg.MarkComplete()
+
+	typ, gotPkg, err := resolveTagType(pkg, nil, "Thing", make(tagTypeCache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPkg != pkg {
+		t.Fatalf("got pkg %v, want %v", gotPkg, pkg)
+	}
+	if typ != named {
+		t.Fatalf("got %v, want %v", typ, named)
+	}
+}
+
+// TestResolveTagTypeAlias exercises a locally-aliased import, e.g. a file
+// with `import tm "time"` and a directive referencing `tm.Time` - the local
+// alias "tm", not the package's canonical name "time", is what must resolve.
+func TestResolveTagTypeAlias(t *testing.T) {
+	timePkg := types.NewPackage("time", "time")
+	named := types.NewNamed(types.NewTypeName(token.NoPos, timePkg, "Time", nil), types.NewStruct(nil, nil), nil)
+	timePkg.Scope().Insert(named.Obj())
+	timePkg.MarkComplete()
+
+	imports := map[string]*types.Package{"tm": timePkg}
+
+	typ, gotPkg, err := resolveTagType(nil, imports, "tm.Time", make(tagTypeCache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPkg != timePkg {
+		t.Fatalf("got pkg %v, want %v", gotPkg, timePkg)
+	}
+	if typ != named {
+		t.Fatalf("got %v, want %v", typ, named)
+	}
+}
+
+func TestResolveTagTypeUnknownPackage(t *testing.T) {
+	if _, _, err := resolveTagType(nil, nil, "tm.Time", make(tagTypeCache)); err == nil {
+		t.Fatal("expected an error for an unimported qualifier, got nil")
+	}
+}
+
+func TestParseTagTypes(t *testing.T) {
+	timePkg := types.NewPackage("time", "time")
+	named := types.NewNamed(types.NewTypeName(token.NoPos, timePkg, "Time", nil), types.NewStruct(nil, nil), nil)
+	timePkg.Scope().Insert(named.Obj())
+	timePkg.MarkComplete()
+
+	imports := map[string]*types.Package{"time": timePkg}
+
+	tagTypes, err := parseTagTypes(nil, imports, `foo:"Bar" baz:"qux[struct{}],Compare[time.Time]"`, make(tagTypeCache))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tagTypes) != 2 {
+		t.Fatalf("got %d TagTypes, want 2: %+v", len(tagTypes), tagTypes)
+	}
+	if tagTypes[0].Name != "struct{}" {
+		t.Fatalf("got %q, want struct{}", tagTypes[0].Name)
+	}
+	if tagTypes[1].Name != "time.Time" || tagTypes[1].Pkg != timePkg {
+		t.Fatalf("got %+v, want Name=time.Time Pkg=%v", tagTypes[1], timePkg)
+	}
+}
+
+// TestResolveTagTypeFullPathUsesCache guards the tagTypeCache: a full
+// import-path reference (e.g. github.com/foo/bar.Thing) must be resolved
+// from a pre-populated cache entry rather than shelling out to
+// packages.Load again. A cache miss here would reach packages.Load for an
+// import path that doesn't exist on disk and fail the test.
+func TestResolveTagTypeFullPathUsesCache(t *testing.T) {
+	barPkg := types.NewPackage("github.com/foo/bar", "bar")
+	named := types.NewNamed(types.NewTypeName(token.NoPos, barPkg, "Thing", nil), types.NewStruct(nil, nil), nil)
+	barPkg.Scope().Insert(named.Obj())
+	barPkg.MarkComplete()
+
+	cache := tagTypeCache{"github.com/foo/bar": barPkg}
+
+	typ, gotPkg, err := resolveTagType(nil, nil, "github.com/foo/bar.Thing", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPkg != barPkg {
+		t.Fatalf("got pkg %v, want %v", gotPkg, barPkg)
+	}
+	if typ != named {
+		t.Fatalf("got %v, want %v", typ, named)
+	}
+}
@@ -0,0 +1,42 @@
+package typewriter
+
+import "go/types"
+
+// Type represents a single Go type declaration carrying a typewriter
+// directive, along with everything a TypeWriter needs to generate code
+// against it.
+type Type struct {
+	Name string
+
+	// test is appended to generated file names when the type was declared
+	// in a _test.go file, so generated code doesn't leak into non-test builds.
+	test string
+
+	// Tag is the raw directive tag text following the directive marker, e.g.
+	// `foo:"Bar" baz:"qux[struct{}],thing"` for a comment of
+	// `// +test foo:"Bar" baz:"qux[struct{}],thing"`.
+	Tag string
+
+	// TagTypes holds the bracketed type references found within Tag (e.g.
+	// the `time.Time` in `Compare[time.Time]`), resolved to go/types.Type
+	// via parseTagTypes.
+	TagTypes []TagType
+
+	Pkg *Package
+
+	// Type is the fully resolved go/types.Type for this Type, populated by
+	// getPackages now that packages are loaded via go/packages rather than
+	// go/parser.
+	Type types.Type
+}
+
+func (t Type) String() string {
+	return t.Name
+}
+
+// typeByName sorts Types so generated output is stable from run to run.
+type typeByName []Type
+
+func (t typeByName) Len() int           { return len(t) }
+func (t typeByName) Less(i, j int) bool { return t[i].Name < t[j].Name }
+func (t typeByName) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }